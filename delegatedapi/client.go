@@ -0,0 +1,327 @@
+// Package delegatedapi provides a client for SPIRE's Delegated Identity API.
+//
+// The Delegated Identity API is exposed by the SPIRE Agent over a separate,
+// admin-only socket and allows a caller to fetch and subscribe to SVIDs and
+// bundles on behalf of other workloads, identified by a set of selectors,
+// rather than the identity of the caller itself (as the Workload API does).
+// This is the extension point used by proxy-style callers such as service
+// meshes and ingress controllers that mint SVIDs for workloads they front.
+package delegatedapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/jwtbundle"
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/internal/backoff"
+	"github.com/spiffe/go-spiffe/v2/logger"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/jwtsvid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+	delegatedidentityv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/agent/delegatedidentity/v1"
+	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Client is a Delegated Identity API client.
+type Client struct {
+	conn     *grpc.ClientConn
+	diClient delegatedidentityv1.DelegatedIdentityClient
+	config   clientConfig
+	backoff  *backoff.Backoff
+}
+
+// New dials the Delegated Identity API on the agent's admin socket and
+// returns a client.
+func New(ctx context.Context, options ...ClientOption) (*Client, error) {
+	c := &Client{
+		config:  defaultClientConfig(),
+		backoff: backoff.New(),
+	}
+	for _, opt := range options {
+		opt.configureClient(&c.config)
+	}
+
+	if c.config.address == "" {
+		return nil, errors.New("delegated identity endpoint socket address is not configured")
+	}
+
+	var err error
+	c.conn, err = c.newConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.diClient = delegatedidentityv1.NewDelegatedIdentityClient(c.conn)
+	return c, nil
+}
+
+// Close closes the client.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// X509SVIDWatcher receives X509-SVID updates for the selectors passed to
+// SubscribeToX509SVIDs.
+type X509SVIDWatcher interface {
+	// OnX509SVIDsUpdate is called with the latest X509-SVIDs matching the
+	// subscribed selectors.
+	OnX509SVIDsUpdate([]*x509svid.SVID)
+
+	// OnX509SVIDsWatchError is called when there is a problem establishing
+	// or maintaining connectivity with the Delegated Identity API.
+	OnX509SVIDsWatchError(error)
+}
+
+// X509BundlesWatcher receives X.509 bundle updates from the Delegated
+// Identity API.
+type X509BundlesWatcher interface {
+	// OnX509BundlesUpdate is called with the latest X.509 bundles.
+	OnX509BundlesUpdate(*x509bundle.Set)
+
+	// OnX509BundlesWatchError is called when there is a problem establishing
+	// or maintaining connectivity with the Delegated Identity API.
+	OnX509BundlesWatchError(error)
+}
+
+// JWTBundlesWatcher receives JWT bundle updates from the Delegated Identity
+// API.
+type JWTBundlesWatcher interface {
+	// OnJWTBundlesUpdate is called with the latest JWT bundles.
+	OnJWTBundlesUpdate(*jwtbundle.Set)
+
+	// OnJWTBundlesWatchError is called when there is a problem establishing
+	// or maintaining connectivity with the Delegated Identity API.
+	OnJWTBundlesWatchError(error)
+}
+
+// SubscribeToX509SVIDs subscribes to the X509-SVIDs of the workloads
+// matching selectors, i.e. the workloads the caller is fetching identities
+// on behalf of. The watcher receives updates until ctx is done or the
+// Delegated Identity API rejects the subscription with InvalidArgument or
+// PermissionDenied.
+func (c *Client) SubscribeToX509SVIDs(ctx context.Context, selectors []*types.Selector, watcher X509SVIDWatcher) error {
+	for {
+		err := c.subscribeToX509SVIDs(ctx, selectors, watcher)
+		watcher.OnX509SVIDsWatchError(err)
+		err = c.handleWatchError(ctx, err)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) subscribeToX509SVIDs(ctx context.Context, selectors []*types.Selector, watcher X509SVIDWatcher) error {
+	c.config.log.Debugf("Subscribing to delegated X509-SVIDs")
+	stream, err := c.diClient.SubscribeToX509SVIDs(ctx, &delegatedidentityv1.SubscribeToX509SVIDsRequest{
+		Selectors: selectors,
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		c.backoff.Reset()
+		svids, err := parseDelegatedX509SVIDs(resp)
+		if err != nil {
+			c.config.log.Errorf("Failed to parse delegated X509-SVID response: %v", err)
+			watcher.OnX509SVIDsWatchError(err)
+			continue
+		}
+		watcher.OnX509SVIDsUpdate(svids)
+	}
+}
+
+// SubscribeToX509Bundles subscribes to the X.509 bundles known to the
+// SPIRE Agent.
+func (c *Client) SubscribeToX509Bundles(ctx context.Context, watcher X509BundlesWatcher) error {
+	for {
+		err := c.subscribeToX509Bundles(ctx, watcher)
+		watcher.OnX509BundlesWatchError(err)
+		err = c.handleWatchError(ctx, err)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) subscribeToX509Bundles(ctx context.Context, watcher X509BundlesWatcher) error {
+	c.config.log.Debugf("Subscribing to delegated X.509 bundles")
+	stream, err := c.diClient.SubscribeToX509Bundles(ctx, &delegatedidentityv1.SubscribeToX509BundlesRequest{})
+	if err != nil {
+		return err
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		c.backoff.Reset()
+		bundles, err := parseDelegatedX509Bundles(resp)
+		if err != nil {
+			c.config.log.Errorf("Failed to parse delegated X.509 bundle response: %v", err)
+			watcher.OnX509BundlesWatchError(err)
+			continue
+		}
+		watcher.OnX509BundlesUpdate(bundles)
+	}
+}
+
+// FetchJWTSVIDs fetches the JWT-SVIDs of the workload matching selectors for
+// the given audience.
+func (c *Client) FetchJWTSVIDs(ctx context.Context, selectors []*types.Selector, audience string) ([]*jwtsvid.SVID, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resp, err := c.diClient.FetchJWTSVIDs(ctx, &delegatedidentityv1.FetchJWTSVIDsRequest{
+		Selectors: selectors,
+		Audience:  []string{audience},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Svids) == 0 {
+		return nil, errors.New("there were no SVIDs in the response")
+	}
+
+	svids := make([]*jwtsvid.SVID, 0, len(resp.Svids))
+	for _, svid := range resp.Svids {
+		s, err := jwtsvid.ParseInsecure(svid.Svid, []string{audience})
+		if err != nil {
+			return nil, err
+		}
+		svids = append(svids, s)
+	}
+	return svids, nil
+}
+
+// SubscribeToJWTBundles subscribes to the JWT bundles known to the SPIRE
+// Agent.
+func (c *Client) SubscribeToJWTBundles(ctx context.Context, watcher JWTBundlesWatcher) error {
+	for {
+		err := c.subscribeToJWTBundles(ctx, watcher)
+		watcher.OnJWTBundlesWatchError(err)
+		err = c.handleWatchError(ctx, err)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) subscribeToJWTBundles(ctx context.Context, watcher JWTBundlesWatcher) error {
+	c.config.log.Debugf("Subscribing to delegated JWT bundles")
+	stream, err := c.diClient.SubscribeToJWTBundles(ctx, &delegatedidentityv1.SubscribeToJWTBundlesRequest{})
+	if err != nil {
+		return err
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		c.backoff.Reset()
+		bundles, err := parseDelegatedJWTBundles(resp)
+		if err != nil {
+			c.config.log.Errorf("Failed to parse delegated JWT bundle response: %v", err)
+			watcher.OnJWTBundlesWatchError(err)
+			continue
+		}
+		watcher.OnJWTBundlesUpdate(bundles)
+	}
+}
+
+func (c *Client) newConn(ctx context.Context) (*grpc.ClientConn, error) {
+	c.config.dialOptions = append(c.config.dialOptions, grpc.WithInsecure())
+	return grpc.DialContext(ctx, c.config.address, c.config.dialOptions...)
+}
+
+func (c *Client) handleWatchError(ctx context.Context, err error) error {
+	code := status.Code(err)
+	if code == codes.Canceled {
+		return err
+	}
+
+	if code == codes.InvalidArgument || code == codes.PermissionDenied {
+		c.config.log.Errorf("Canceling watch: %v", err)
+		return err
+	}
+
+	c.config.log.Errorf("Failed to watch the Delegated Identity API: %v", err)
+	delay := c.backoff.Duration()
+	c.config.log.Debugf("Retrying watch in %s", delay)
+	select {
+	case <-time.After(delay):
+		return nil
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func defaultClientConfig() clientConfig {
+	return clientConfig{
+		log: logger.Null,
+	}
+}
+
+func parseDelegatedX509SVIDs(resp *delegatedidentityv1.SubscribeToX509SVIDsResponse) ([]*x509svid.SVID, error) {
+	svids := make([]*x509svid.SVID, 0, len(resp.X509Svids))
+	for _, svid := range resp.X509Svids {
+		certDER := bytes.Join(svid.X509Svid.CertChain, nil)
+		s, err := x509svid.ParseRaw(certDER, svid.X509SvidKey)
+		if err != nil {
+			return nil, err
+		}
+		svids = append(svids, s)
+	}
+	return svids, nil
+}
+
+func parseDelegatedX509Bundles(resp *delegatedidentityv1.SubscribeToX509BundlesResponse) (*x509bundle.Set, error) {
+	bundles := []*x509bundle.Bundle{}
+	for tdID, bundle := range resp.CaCertificates {
+		td, err := spiffeid.TrustDomainFromString(tdID)
+		if err != nil {
+			return nil, err
+		}
+		certs, err := x509.ParseCertificates(bundle)
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, x509bundle.FromX509Authorities(td, certs))
+	}
+	return x509bundle.NewSet(bundles...), nil
+}
+
+func parseDelegatedJWTBundles(resp *delegatedidentityv1.SubscribeToJWTBundlesResponse) (*jwtbundle.Set, error) {
+	bundles := []*jwtbundle.Bundle{}
+	for tdID, b := range resp.Bundles {
+		td, err := spiffeid.TrustDomainFromString(tdID)
+		if err != nil {
+			return nil, err
+		}
+		bundle, err := jwtbundle.Parse(td, b)
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, bundle)
+	}
+	return jwtbundle.NewSet(bundles...), nil
+}
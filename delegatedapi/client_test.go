@@ -0,0 +1,179 @@
+package delegatedapi
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/internal/backoff"
+	delegatedidentityv1 "github.com/spiffe/spire-api-sdk/proto/spire/api/agent/delegatedidentity/v1"
+	"github.com/spiffe/spire-api-sdk/proto/spire/api/types"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// mustX509SVIDDER returns a self-signed certificate and PKCS8 private key
+// for id, DER-encoded the way the Delegated Identity API delivers them.
+func mustX509SVIDDER(t *testing.T, id string) (certDER, keyDER []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey failed: %v", err)
+	}
+
+	uri, err := url.Parse(id)
+	if err != nil {
+		t.Fatalf("url.Parse(%q) failed: %v", id, err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: id},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		URIs:         []*url.URL{uri},
+	}
+	certDER, err = x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate failed: %v", err)
+	}
+	keyDER, err = x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("x509.MarshalPKCS8PrivateKey failed: %v", err)
+	}
+	return certDER, keyDER
+}
+
+func TestParseDelegatedX509SVIDs(t *testing.T) {
+	certDER, keyDER := mustX509SVIDDER(t, "spiffe://example.org/workload")
+
+	svids, err := parseDelegatedX509SVIDs(&delegatedidentityv1.SubscribeToX509SVIDsResponse{
+		X509Svids: []*delegatedidentityv1.X509SVIDWithKey{
+			{
+				X509Svid: &types.X509SVID{
+					Id:        &types.SPIFFEID{TrustDomain: "example.org", Path: "/workload"},
+					CertChain: [][]byte{certDER},
+				},
+				X509SvidKey: keyDER,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("parseDelegatedX509SVIDs failed: %v", err)
+	}
+	if len(svids) != 1 {
+		t.Fatalf("parseDelegatedX509SVIDs() returned %d SVIDs, want 1", len(svids))
+	}
+	if svids[0].ID.String() != "spiffe://example.org/workload" {
+		t.Fatalf("parseDelegatedX509SVIDs() SVID ID = %q, want %q", svids[0].ID, "spiffe://example.org/workload")
+	}
+}
+
+func TestParseDelegatedX509SVIDsEmpty(t *testing.T) {
+	svids, err := parseDelegatedX509SVIDs(&delegatedidentityv1.SubscribeToX509SVIDsResponse{})
+	if err != nil {
+		t.Fatalf("parseDelegatedX509SVIDs failed: %v", err)
+	}
+	if len(svids) != 0 {
+		t.Fatalf("parseDelegatedX509SVIDs() returned %d SVIDs, want 0", len(svids))
+	}
+}
+
+func TestParseDelegatedX509SVIDsBadCert(t *testing.T) {
+	_, err := parseDelegatedX509SVIDs(&delegatedidentityv1.SubscribeToX509SVIDsResponse{
+		X509Svids: []*delegatedidentityv1.X509SVIDWithKey{
+			{
+				X509Svid:    &types.X509SVID{CertChain: [][]byte{[]byte("not a cert")}},
+				X509SvidKey: []byte("not a key"),
+			},
+		},
+	})
+	if err == nil {
+		t.Fatal("parseDelegatedX509SVIDs() with malformed DER succeeded, want error")
+	}
+}
+
+func TestParseDelegatedX509Bundles(t *testing.T) {
+	certDER, _ := mustX509SVIDDER(t, "spiffe://example.org/workload")
+
+	bundles, err := parseDelegatedX509Bundles(&delegatedidentityv1.SubscribeToX509BundlesResponse{
+		CaCertificates: map[string][]byte{
+			"example.org": certDER,
+		},
+	})
+	if err != nil {
+		t.Fatalf("parseDelegatedX509Bundles failed: %v", err)
+	}
+	if len(bundles.Bundles()) != 1 {
+		t.Fatalf("parseDelegatedX509Bundles() returned %d bundles, want 1", len(bundles.Bundles()))
+	}
+}
+
+func TestParseDelegatedX509BundlesBadTrustDomain(t *testing.T) {
+	_, err := parseDelegatedX509Bundles(&delegatedidentityv1.SubscribeToX509BundlesResponse{
+		CaCertificates: map[string][]byte{
+			"not a trust domain!": nil,
+		},
+	})
+	if err == nil {
+		t.Fatal("parseDelegatedX509Bundles() with an invalid trust domain succeeded, want error")
+	}
+}
+
+func TestParseDelegatedJWTBundles(t *testing.T) {
+	bundles, err := parseDelegatedJWTBundles(&delegatedidentityv1.SubscribeToJWTBundlesResponse{
+		Bundles: map[string][]byte{
+			"example.org": []byte(`{"keys":[]}`),
+		},
+	})
+	if err != nil {
+		t.Fatalf("parseDelegatedJWTBundles failed: %v", err)
+	}
+	if len(bundles.Bundles()) != 1 {
+		t.Fatalf("parseDelegatedJWTBundles() returned %d bundles, want 1", len(bundles.Bundles()))
+	}
+}
+
+func TestParseDelegatedJWTBundlesBadTrustDomain(t *testing.T) {
+	_, err := parseDelegatedJWTBundles(&delegatedidentityv1.SubscribeToJWTBundlesResponse{
+		Bundles: map[string][]byte{
+			"not a trust domain!": []byte(`{"keys":[]}`),
+		},
+	})
+	if err == nil {
+		t.Fatal("parseDelegatedJWTBundles() with an invalid trust domain succeeded, want error")
+	}
+}
+
+func TestHandleWatchErrorCancelsOnPermissionDeniedAndInvalidArgument(t *testing.T) {
+	for _, code := range []codes.Code{codes.PermissionDenied, codes.InvalidArgument} {
+		c := &Client{config: defaultClientConfig(), backoff: backoff.New()}
+		watchErr := status.Error(code, "not allowed")
+
+		err := c.handleWatchError(context.Background(), watchErr)
+		if err != watchErr {
+			t.Fatalf("handleWatchError(%s) = %v, want the original error returned so the watch is canceled", code, err)
+		}
+	}
+}
+
+func TestHandleWatchErrorBacksOffOnOtherCodes(t *testing.T) {
+	c := &Client{config: defaultClientConfig(), backoff: backoff.New()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := c.handleWatchError(ctx, status.Error(codes.Unavailable, "down")); err != ctx.Err() {
+		t.Fatalf("handleWatchError() = %v, want ctx.Err() once ctx is done", err)
+	}
+}
@@ -0,0 +1,50 @@
+package delegatedapi
+
+import (
+	"github.com/spiffe/go-spiffe/v2/logger"
+
+	"google.golang.org/grpc"
+)
+
+type clientConfig struct {
+	address     string
+	dialOptions []grpc.DialOption
+	log         logger.Logger
+}
+
+// ClientOption is an option used when creating a new Client.
+type ClientOption interface {
+	configureClient(*clientConfig)
+}
+
+type clientOptionFunc func(*clientConfig)
+
+func (fn clientOptionFunc) configureClient(c *clientConfig) {
+	fn(c)
+}
+
+// WithAddr provides an address to use when dialing the Delegated Identity
+// API. The value is handed to gRPC unchanged as the dial target (e.g.
+// "unix:///path/to/admin.sock"); unlike the Workload API client's WithAddr,
+// it is not parsed or validated, so a "tcp://" address is not translated
+// into a host:port target.
+func WithAddr(addr string) ClientOption {
+	return clientOptionFunc(func(c *clientConfig) {
+		c.address = addr
+	})
+}
+
+// WithDialOptions provides extra gRPC dial options when dialing the
+// Delegated Identity API.
+func WithDialOptions(options ...grpc.DialOption) ClientOption {
+	return clientOptionFunc(func(c *clientConfig) {
+		c.dialOptions = append(c.dialOptions, options...)
+	})
+}
+
+// WithLogger provides a logger to the client.
+func WithLogger(log logger.Logger) ClientOption {
+	return clientOptionFunc(func(c *clientConfig) {
+		c.log = log
+	})
+}
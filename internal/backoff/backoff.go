@@ -0,0 +1,38 @@
+// Package backoff provides the retry backoff shared by the Workload API and
+// Delegated Identity API clients so that a fix to the backoff algorithm
+// only has to be made once.
+package backoff
+
+import "time"
+
+const (
+	minInterval = 10 * time.Millisecond
+	maxInterval = 30 * time.Second
+	factor      = 2
+)
+
+// Backoff tracks the delay between retries of a failed watch or
+// subscription.
+type Backoff struct {
+	interval time.Duration
+}
+
+// New returns a Backoff starting at the minimum interval.
+func New() *Backoff {
+	return &Backoff{interval: minInterval}
+}
+
+// Duration returns the next backoff duration and advances the interval.
+func (b *Backoff) Duration() time.Duration {
+	d := b.interval
+	b.interval *= factor
+	if b.interval > maxInterval {
+		b.interval = maxInterval
+	}
+	return d
+}
+
+// Reset resets the backoff back to its minimum interval.
+func (b *Backoff) Reset() {
+	b.interval = minInterval
+}
@@ -0,0 +1,37 @@
+package backoff
+
+import "testing"
+
+func TestBackoffDurationGrowsAndCaps(t *testing.T) {
+	b := New()
+
+	first := b.Duration()
+	if first != minInterval {
+		t.Fatalf("first backoff = %s, want %s", first, minInterval)
+	}
+
+	prev := first
+	for i := 0; i < 20; i++ {
+		d := b.Duration()
+		if d < prev {
+			t.Fatalf("backoff decreased: %s then %s", prev, d)
+		}
+		if d > maxInterval {
+			t.Fatalf("backoff %s exceeded max %s", d, maxInterval)
+		}
+		prev = d
+	}
+}
+
+func TestBackoffReset(t *testing.T) {
+	b := New()
+
+	for i := 0; i < 5; i++ {
+		b.Duration()
+	}
+
+	b.Reset()
+	if d := b.Duration(); d != minInterval {
+		t.Fatalf("backoff after reset = %s, want %s", d, minInterval)
+	}
+}
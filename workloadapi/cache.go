@@ -0,0 +1,111 @@
+package workloadapi
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/jwtbundle"
+	"github.com/spiffe/go-spiffe/v2/svid/jwtsvid"
+)
+
+// Cache is a pluggable storage backend for SVIDs and bundles retrieved from
+// the Workload API. When a Cache is configured via WithCache, the client
+// populates it on every successful fetch and, if the Workload API becomes
+// unreachable, serves material from it instead of failing outright,
+// provided that material has not itself expired (Load methods return
+// ErrCacheExpired once every stored SVID has expired; a stored set in which
+// only some SVIDs have expired is returned whole). This lets a workload
+// survive agent restarts and other socket unavailability windows.
+type Cache interface {
+	// LoadX509Context returns the last X.509 context stored by
+	// StoreX509Context, or ErrCacheExpired if every stored SVID has expired.
+	LoadX509Context() (*X509Context, error)
+	// StoreX509Context stores the given X.509 context.
+	StoreX509Context(*X509Context) error
+
+	// LoadJWTBundles returns the last JWT bundles stored by
+	// StoreJWTBundles.
+	LoadJWTBundles() (*jwtbundle.Set, error)
+	// StoreJWTBundles stores the given JWT bundles.
+	StoreJWTBundles(*jwtbundle.Set) error
+
+	// LoadJWTSVID returns the last JWT-SVID stored for the given parameters
+	// and hint by StoreJWTSVID, or ErrCacheExpired if it has expired. hint
+	// is the same hint passed to FetchJWTSVIDWithHint, or "" for a JWT-SVID
+	// fetched via FetchJWTSVID, so that two hints for the same parameters
+	// are cached independently instead of overwriting one another.
+	LoadJWTSVID(params jwtsvid.Params, hint string) (*jwtsvid.SVID, error)
+	// StoreJWTSVID stores the given JWT-SVID under the given parameters and
+	// hint.
+	StoreJWTSVID(params jwtsvid.Params, hint string, svid *jwtsvid.SVID) error
+
+	// LoadJWTSVIDs returns the last set of JWT-SVIDs stored for the given
+	// parameters by StoreJWTSVIDs, or ErrCacheExpired if every stored SVID
+	// has expired.
+	LoadJWTSVIDs(params jwtsvid.Params) ([]*jwtsvid.SVID, error)
+	// StoreJWTSVIDs stores the given set of JWT-SVIDs under the given
+	// parameters.
+	StoreJWTSVIDs(params jwtsvid.Params, svids []*jwtsvid.SVID) error
+}
+
+// ErrServedFromCache is passed to a watcher's error callback alongside an
+// update that was retrieved from the configured Cache because the Workload
+// API could not be reached.
+var ErrServedFromCache = errors.New("workloadapi: served from cache")
+
+// ErrCacheExpired is returned by a Cache's Load methods when the stored
+// material has expired and so cannot be served as a fallback.
+var ErrCacheExpired = errors.New("workloadapi: cached credential has expired")
+
+// x509ContextExpired reports whether every SVID in the X.509 context has
+// expired.
+func x509ContextExpired(x509Context *X509Context) bool {
+	now := time.Now()
+	for _, svid := range x509Context.SVIDs {
+		if len(svid.Certificates) == 0 || now.Before(svid.Certificates[0].NotAfter) {
+			return false
+		}
+	}
+	return len(x509Context.SVIDs) > 0
+}
+
+// jwtSVIDExpired reports whether the JWT-SVID has expired.
+func jwtSVIDExpired(svid *jwtsvid.SVID) bool {
+	return !svid.Expiry.IsZero() && time.Now().After(svid.Expiry)
+}
+
+// jwtSVIDsExpired reports whether every JWT-SVID in svids has expired.
+func jwtSVIDsExpired(svids []*jwtsvid.SVID) bool {
+	for _, svid := range svids {
+		if !jwtSVIDExpired(svid) {
+			return false
+		}
+	}
+	return len(svids) > 0
+}
+
+type cacheOption struct {
+	cache Cache
+}
+
+func (o cacheOption) configureClient(c *clientConfig) {
+	c.cache = o.cache
+}
+
+// WithCache configures the client to use the given Cache to persist
+// successful responses from the Workload API and to fall back to them when
+// the Workload API is unreachable. Responses are persisted by the Fetch
+// methods and by WatchJWTSVIDs; WatchX509Context and WatchJWTBundles serve
+// from the cache but do not store the updates they receive.
+func WithCache(cache Cache) ClientOption {
+	return cacheOption{cache: cache}
+}
+
+// jwtSVIDCacheKey returns the cache key identifying a JWT-SVID for the given
+// parameters and hint. Distinct hints for the same parameters (e.g. "the
+// database one" vs. "the mail one") must land in distinct slots, so hint is
+// folded into the key rather than ignored.
+func jwtSVIDCacheKey(params jwtsvid.Params, hint string) string {
+	return strings.Join(append([]string{params.Subject.String(), params.Audience, hint}, params.ExtraAudiences...), "|")
+}
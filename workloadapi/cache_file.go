@@ -0,0 +1,405 @@
+package workloadapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/jwtbundle"
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/jwtsvid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+)
+
+// FileCache is a Cache implementation that materializes SVIDs and bundles
+// to disk as PEM (certificates and keys) and JSON (JWT-SVIDs and bundles)
+// files. It is the building block for spiffe-helper-style disk
+// materialization on top of this package. It is safe for concurrent use.
+type FileCache struct {
+	dir      string
+	certMode os.FileMode
+	keyMode  os.FileMode
+
+	mu sync.Mutex
+}
+
+// FileCacheOption customizes a FileCache.
+type FileCacheOption func(*FileCache)
+
+// WithCertFileMode sets the file mode used when writing certificate and
+// bundle files. Defaults to 0644.
+func WithCertFileMode(mode os.FileMode) FileCacheOption {
+	return func(c *FileCache) {
+		c.certMode = mode
+	}
+}
+
+// WithKeyFileMode sets the file mode used when writing private key and
+// other secret-bearing files (including cached JWT-SVIDs, which are bearer
+// credentials). Defaults to 0600.
+func WithKeyFileMode(mode os.FileMode) FileCacheOption {
+	return func(c *FileCache) {
+		c.keyMode = mode
+	}
+}
+
+// NewFileCache returns a FileCache that materializes cached material under
+// dir. The directory must already exist.
+func NewFileCache(dir string, options ...FileCacheOption) *FileCache {
+	c := &FileCache{
+		dir:      dir,
+		certMode: 0644,
+		keyMode:  0600,
+	}
+	for _, opt := range options {
+		opt(c)
+	}
+	return c
+}
+
+const (
+	x509ManifestFile = "x509_context.json"
+	jwtBundlesFile   = "jwt_bundles.json"
+	jwtSVIDsFile     = "jwt_svids.json"
+	jwtSVIDSetsFile  = "jwt_svid_sets.json"
+)
+
+// x509ContextManifest is the on-disk representation of an X.509 context.
+// Certificates and keys are stored as detached PEM-encoded files so they can
+// be consumed directly by tools that expect PEM on disk; the manifest
+// just records which files belong to which identity.
+type x509ContextManifest struct {
+	SVIDs   []x509SVIDEntry   `json:"svids"`
+	Bundles map[string]string `json:"bundles"` // trust domain -> bundle file
+}
+
+type x509SVIDEntry struct {
+	ID       string `json:"id"`
+	CertPath string `json:"cert_path"`
+	KeyPath  string `json:"key_path"`
+}
+
+// LoadX509Context returns the last X.509 context stored by
+// StoreX509Context.
+func (c *FileCache) LoadX509Context() (*X509Context, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var manifest x509ContextManifest
+	if err := c.loadJSON(x509ManifestFile, &manifest); err != nil {
+		return nil, err
+	}
+
+	svids := make([]*x509svid.SVID, 0, len(manifest.SVIDs))
+	for _, entry := range manifest.SVIDs {
+		certPEM, err := os.ReadFile(filepath.Join(c.dir, entry.CertPath))
+		if err != nil {
+			return nil, err
+		}
+		keyPEM, err := os.ReadFile(filepath.Join(c.dir, entry.KeyPath))
+		if err != nil {
+			return nil, err
+		}
+		svid, err := x509svid.Parse(certPEM, keyPEM)
+		if err != nil {
+			return nil, err
+		}
+		svids = append(svids, svid)
+	}
+
+	bundles := []*x509bundle.Bundle{}
+	for tdID, bundlePath := range manifest.Bundles {
+		td, err := spiffeid.TrustDomainFromString(tdID)
+		if err != nil {
+			return nil, err
+		}
+		bundlePEM, err := os.ReadFile(filepath.Join(c.dir, bundlePath))
+		if err != nil {
+			return nil, err
+		}
+		bundle, err := x509bundle.Parse(td, bundlePEM)
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, bundle)
+	}
+
+	x509Context := &X509Context{
+		SVIDs:   svids,
+		Bundles: x509bundle.NewSet(bundles...),
+	}
+	if x509ContextExpired(x509Context) {
+		return nil, ErrCacheExpired
+	}
+	return x509Context, nil
+}
+
+// StoreX509Context stores the given X.509 context, removing any svid-*/
+// bundle-* files left behind by a previous StoreX509Context whose SVID or
+// bundle set has since shrunk (e.g. a revoked identity) so the orphaned
+// private key material doesn't linger on disk.
+func (c *FileCache) StoreX509Context(x509Context *X509Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stalePaths, err := c.x509ContextFilePaths()
+	if err != nil {
+		return err
+	}
+
+	manifest := x509ContextManifest{
+		Bundles: make(map[string]string),
+	}
+
+	for i, svid := range x509Context.SVIDs {
+		certPEM, keyPEM, err := svid.Marshal()
+		if err != nil {
+			return err
+		}
+
+		certPath := fmt.Sprintf("svid-%d.pem", i)
+		keyPath := fmt.Sprintf("svid-%d-key.pem", i)
+		if err := c.writeFile(certPath, certPEM, c.certMode); err != nil {
+			return err
+		}
+		if err := c.writeFile(keyPath, keyPEM, c.keyMode); err != nil {
+			return err
+		}
+		delete(stalePaths, certPath)
+		delete(stalePaths, keyPath)
+
+		manifest.SVIDs = append(manifest.SVIDs, x509SVIDEntry{
+			ID:       svid.ID.String(),
+			CertPath: certPath,
+			KeyPath:  keyPath,
+		})
+	}
+
+	for _, bundle := range x509Context.Bundles.Bundles() {
+		bundlePEM, err := bundle.Marshal()
+		if err != nil {
+			return err
+		}
+		bundlePath := fmt.Sprintf("bundle-%s.pem", sanitizeTrustDomain(bundle.TrustDomain()))
+		if err := c.writeFile(bundlePath, bundlePEM, c.certMode); err != nil {
+			return err
+		}
+		delete(stalePaths, bundlePath)
+		manifest.Bundles[bundle.TrustDomain().String()] = bundlePath
+	}
+
+	if err := c.storeJSON(x509ManifestFile, manifest, c.certMode); err != nil {
+		return err
+	}
+
+	for path := range stalePaths {
+		if err := os.Remove(filepath.Join(c.dir, path)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// x509ContextFilePaths returns the svid-*/bundle-* file paths recorded in
+// the manifest from the previous StoreX509Context, or an empty set if there
+// is none yet.
+func (c *FileCache) x509ContextFilePaths() (map[string]struct{}, error) {
+	var manifest x509ContextManifest
+	if err := c.loadJSON(x509ManifestFile, &manifest); err != nil {
+		if os.IsNotExist(err) {
+			return map[string]struct{}{}, nil
+		}
+		return nil, err
+	}
+
+	paths := make(map[string]struct{}, 2*len(manifest.SVIDs)+len(manifest.Bundles))
+	for _, entry := range manifest.SVIDs {
+		paths[entry.CertPath] = struct{}{}
+		paths[entry.KeyPath] = struct{}{}
+	}
+	for _, bundlePath := range manifest.Bundles {
+		paths[bundlePath] = struct{}{}
+	}
+	return paths, nil
+}
+
+// LoadJWTBundles returns the last JWT bundles stored by StoreJWTBundles.
+func (c *FileCache) LoadJWTBundles() (*jwtbundle.Set, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var raw map[string][]byte
+	if err := c.loadJSON(jwtBundlesFile, &raw); err != nil {
+		return nil, err
+	}
+
+	bundles := []*jwtbundle.Bundle{}
+	for tdID, jwks := range raw {
+		td, err := spiffeid.TrustDomainFromString(tdID)
+		if err != nil {
+			return nil, err
+		}
+		bundle, err := jwtbundle.Parse(td, jwks)
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, bundle)
+	}
+	return jwtbundle.NewSet(bundles...), nil
+}
+
+// StoreJWTBundles stores the given JWT bundles.
+func (c *FileCache) StoreJWTBundles(bundles *jwtbundle.Set) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	raw := make(map[string][]byte)
+	for _, bundle := range bundles.Bundles() {
+		jwks, err := bundle.Marshal()
+		if err != nil {
+			return err
+		}
+		raw[bundle.TrustDomain().String()] = jwks
+	}
+	return c.storeJSON(jwtBundlesFile, raw, c.certMode)
+}
+
+// LoadJWTSVID returns the last JWT-SVID stored for the given parameters and
+// hint by StoreJWTSVID.
+func (c *FileCache) LoadJWTSVID(params jwtsvid.Params, hint string) (*jwtsvid.SVID, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	raw, err := c.loadJWTSVIDTokens(jwtSVIDsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	token, ok := raw[jwtSVIDCacheKey(params, hint)]
+	if !ok {
+		return nil, errors.New("no JWT-SVID in cache for the given parameters")
+	}
+	audience := append([]string{params.Audience}, params.ExtraAudiences...)
+	svid, err := jwtsvid.ParseInsecure(token, audience)
+	if err != nil {
+		return nil, err
+	}
+	if jwtSVIDExpired(svid) {
+		return nil, ErrCacheExpired
+	}
+	return svid, nil
+}
+
+// StoreJWTSVID stores the given JWT-SVID under the given parameters and
+// hint. The token is a bearer credential, so it is written with the same
+// file mode used for private keys.
+func (c *FileCache) StoreJWTSVID(params jwtsvid.Params, hint string, svid *jwtsvid.SVID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	raw, err := c.loadJWTSVIDTokens(jwtSVIDsFile)
+	if err != nil {
+		return err
+	}
+	raw[jwtSVIDCacheKey(params, hint)] = svid.Marshal()
+	return c.storeJSON(jwtSVIDsFile, raw, c.keyMode)
+}
+
+// LoadJWTSVIDs returns the last set of JWT-SVIDs stored for the given
+// parameters by StoreJWTSVIDs.
+func (c *FileCache) LoadJWTSVIDs(params jwtsvid.Params) ([]*jwtsvid.SVID, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var raw map[string][]string
+	if err := c.loadJSON(jwtSVIDSetsFile, &raw); err != nil {
+		return nil, err
+	}
+
+	tokens, ok := raw[jwtSVIDCacheKey(params, "")]
+	if !ok {
+		return nil, errors.New("no JWT-SVIDs in cache for the given parameters")
+	}
+
+	audience := append([]string{params.Audience}, params.ExtraAudiences...)
+	svids := make([]*jwtsvid.SVID, 0, len(tokens))
+	for _, token := range tokens {
+		svid, err := jwtsvid.ParseInsecure(token, audience)
+		if err != nil {
+			return nil, err
+		}
+		svids = append(svids, svid)
+	}
+	if jwtSVIDsExpired(svids) {
+		return nil, ErrCacheExpired
+	}
+	return svids, nil
+}
+
+// StoreJWTSVIDs stores the given set of JWT-SVIDs under the given
+// parameters. The tokens are bearer credentials, so they are written with
+// the same file mode used for private keys.
+func (c *FileCache) StoreJWTSVIDs(params jwtsvid.Params, svids []*jwtsvid.SVID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var raw map[string][]string
+	if err := c.loadJSON(jwtSVIDSetsFile, &raw); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if raw == nil {
+		raw = make(map[string][]string)
+	}
+
+	tokens := make([]string, 0, len(svids))
+	for _, svid := range svids {
+		tokens = append(tokens, svid.Marshal())
+	}
+	raw[jwtSVIDCacheKey(params, "")] = tokens
+	return c.storeJSON(jwtSVIDSetsFile, raw, c.keyMode)
+}
+
+// loadJWTSVIDTokens loads the raw token map backing LoadJWTSVID/
+// StoreJWTSVID, returning an empty map rather than failing when the file
+// simply doesn't exist yet. Any other read or parse error is propagated so
+// a transient failure can never be mistaken for "nothing cached" and
+// silently wipe out the rest of the file on the next store.
+func (c *FileCache) loadJWTSVIDTokens(name string) (map[string]string, error) {
+	var raw map[string]string
+	if err := c.loadJSON(name, &raw); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		raw = make(map[string]string)
+	}
+	return raw, nil
+}
+
+func (c *FileCache) writeFile(name string, data []byte, mode os.FileMode) error {
+	return os.WriteFile(filepath.Join(c.dir, name), data, mode)
+}
+
+func (c *FileCache) storeJSON(name string, v interface{}, mode os.FileMode) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return c.writeFile(name, data, mode)
+}
+
+func (c *FileCache) loadJSON(name string, v interface{}) error {
+	data, err := os.ReadFile(filepath.Join(c.dir, name))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func sanitizeTrustDomain(td spiffeid.TrustDomain) string {
+	return strings.ReplaceAll(td.String(), "/", "_")
+}
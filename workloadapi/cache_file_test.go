@@ -0,0 +1,150 @@
+package workloadapi
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/jwtbundle"
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/jwtsvid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+)
+
+// mustX509SVID returns a self-signed X509-SVID for id, suitable for
+// exercising FileCache's on-disk SVID/key handling in tests.
+func mustX509SVID(t *testing.T, id spiffeid.ID) *x509svid.SVID {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey failed: %v", err)
+	}
+
+	uri, err := url.Parse(id.String())
+	if err != nil {
+		t.Fatalf("url.Parse(%q) failed: %v", id, err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: id.String()},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     futureExpiry(),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		URIs:         []*url.URL{uri},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate failed: %v", err)
+	}
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate failed: %v", err)
+	}
+
+	return &x509svid.SVID{
+		ID:           id,
+		Certificates: []*x509.Certificate{cert},
+		PrivateKey:   key,
+	}
+}
+
+func TestFileCacheJWTBundlesRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	c := NewFileCache(dir)
+
+	td := mustID(t, "spiffe://example.org/workload").TrustDomain()
+	bundle, err := jwtbundle.Parse(td, []byte(`{"keys":[]}`))
+	if err != nil {
+		t.Fatalf("jwtbundle.Parse failed: %v", err)
+	}
+	want := jwtbundle.NewSet(bundle)
+
+	if err := c.StoreJWTBundles(want); err != nil {
+		t.Fatalf("StoreJWTBundles failed: %v", err)
+	}
+
+	got, err := c.LoadJWTBundles()
+	if err != nil {
+		t.Fatalf("LoadJWTBundles failed: %v", err)
+	}
+	if len(got.Bundles()) != 1 || got.Bundles()[0].TrustDomain() != td {
+		t.Fatalf("LoadJWTBundles() = %v, want bundle for trust domain %s", got, td)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, jwtBundlesFile))
+	if err != nil {
+		t.Fatalf("stat %s failed: %v", jwtBundlesFile, err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Fatalf("%s mode = %v, want 0644", jwtBundlesFile, info.Mode().Perm())
+	}
+}
+
+func TestFileCacheStoreX509ContextRemovesStaleFiles(t *testing.T) {
+	dir := t.TempDir()
+	c := NewFileCache(dir)
+
+	first := mustX509SVID(t, mustID(t, "spiffe://example.org/a"))
+	second := mustX509SVID(t, mustID(t, "spiffe://example.org/b"))
+
+	if err := c.StoreX509Context(&X509Context{SVIDs: []*x509svid.SVID{first, second}, Bundles: x509bundle.NewSet()}); err != nil {
+		t.Fatalf("StoreX509Context failed: %v", err)
+	}
+
+	staleCert := filepath.Join(dir, "svid-1.pem")
+	staleKey := filepath.Join(dir, "svid-1-key.pem")
+	if _, err := os.Stat(staleCert); err != nil {
+		t.Fatalf("stat %s failed: %v", staleCert, err)
+	}
+	if _, err := os.Stat(staleKey); err != nil {
+		t.Fatalf("stat %s failed: %v", staleKey, err)
+	}
+
+	if err := c.StoreX509Context(&X509Context{SVIDs: []*x509svid.SVID{first}, Bundles: x509bundle.NewSet()}); err != nil {
+		t.Fatalf("StoreX509Context failed: %v", err)
+	}
+
+	if _, err := os.Stat(staleCert); !os.IsNotExist(err) {
+		t.Fatalf("stale cert %s still present after SVID set shrank: err = %v", staleCert, err)
+	}
+	if _, err := os.Stat(staleKey); !os.IsNotExist(err) {
+		t.Fatalf("stale key %s still present after SVID set shrank: err = %v", staleKey, err)
+	}
+
+	got, err := c.LoadX509Context()
+	if err != nil {
+		t.Fatalf("LoadX509Context failed: %v", err)
+	}
+	if len(got.SVIDs) != 1 || !got.SVIDs[0].ID.MemberOf(first.ID.TrustDomain()) {
+		t.Fatalf("LoadX509Context() = %v, want the surviving SVID only", got)
+	}
+}
+
+func TestFileCacheJWTSVIDsFileModeIsPrivate(t *testing.T) {
+	dir := t.TempDir()
+	c := NewFileCache(dir)
+
+	params := jwtsvid.Params{Subject: mustID(t, "spiffe://example.org/workload"), Audience: "aud"}
+	if err := c.StoreJWTSVIDs(params, nil); err != nil {
+		t.Fatalf("StoreJWTSVIDs failed: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, jwtSVIDSetsFile))
+	if err != nil {
+		t.Fatalf("stat %s failed: %v", jwtSVIDSetsFile, err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("%s mode = %v, want 0600 (JWT-SVIDs are bearer credentials)", jwtSVIDSetsFile, info.Mode().Perm())
+	}
+}
@@ -0,0 +1,123 @@
+package workloadapi
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/jwtbundle"
+	"github.com/spiffe/go-spiffe/v2/svid/jwtsvid"
+)
+
+// MemoryCache is an in-memory Cache implementation. It is safe for
+// concurrent use.
+type MemoryCache struct {
+	mu          sync.RWMutex
+	x509Context *X509Context
+	jwtBundles  *jwtbundle.Set
+	jwtSVIDs    map[string]*jwtsvid.SVID
+	jwtSVIDSets map[string][]*jwtsvid.SVID
+}
+
+// NewMemoryCache returns a new, empty MemoryCache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		jwtSVIDs:    make(map[string]*jwtsvid.SVID),
+		jwtSVIDSets: make(map[string][]*jwtsvid.SVID),
+	}
+}
+
+// LoadX509Context returns the last X.509 context stored by
+// StoreX509Context.
+func (c *MemoryCache) LoadX509Context() (*X509Context, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.x509Context == nil {
+		return nil, errors.New("no X.509 context in cache")
+	}
+	if x509ContextExpired(c.x509Context) {
+		return nil, ErrCacheExpired
+	}
+	return c.x509Context, nil
+}
+
+// StoreX509Context stores the given X.509 context.
+func (c *MemoryCache) StoreX509Context(x509Context *X509Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.x509Context = x509Context
+	return nil
+}
+
+// LoadJWTBundles returns the last JWT bundles stored by StoreJWTBundles.
+func (c *MemoryCache) LoadJWTBundles() (*jwtbundle.Set, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.jwtBundles == nil {
+		return nil, errors.New("no JWT bundles in cache")
+	}
+	return c.jwtBundles, nil
+}
+
+// StoreJWTBundles stores the given JWT bundles.
+func (c *MemoryCache) StoreJWTBundles(bundles *jwtbundle.Set) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.jwtBundles = bundles
+	return nil
+}
+
+// LoadJWTSVID returns the last JWT-SVID stored for the given parameters and
+// hint by StoreJWTSVID.
+func (c *MemoryCache) LoadJWTSVID(params jwtsvid.Params, hint string) (*jwtsvid.SVID, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	svid, ok := c.jwtSVIDs[jwtSVIDCacheKey(params, hint)]
+	if !ok {
+		return nil, errors.New("no JWT-SVID in cache for the given parameters")
+	}
+	if jwtSVIDExpired(svid) {
+		return nil, ErrCacheExpired
+	}
+	return svid, nil
+}
+
+// StoreJWTSVID stores the given JWT-SVID under the given parameters and
+// hint.
+func (c *MemoryCache) StoreJWTSVID(params jwtsvid.Params, hint string, svid *jwtsvid.SVID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.jwtSVIDs[jwtSVIDCacheKey(params, hint)] = svid
+	return nil
+}
+
+// LoadJWTSVIDs returns the last set of JWT-SVIDs stored for the given
+// parameters by StoreJWTSVIDs.
+func (c *MemoryCache) LoadJWTSVIDs(params jwtsvid.Params) ([]*jwtsvid.SVID, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	svids, ok := c.jwtSVIDSets[jwtSVIDCacheKey(params, "")]
+	if !ok {
+		return nil, errors.New("no JWT-SVIDs in cache for the given parameters")
+	}
+	if jwtSVIDsExpired(svids) {
+		return nil, ErrCacheExpired
+	}
+	return svids, nil
+}
+
+// StoreJWTSVIDs stores the given set of JWT-SVIDs under the given
+// parameters.
+func (c *MemoryCache) StoreJWTSVIDs(params jwtsvid.Params, svids []*jwtsvid.SVID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.jwtSVIDSets[jwtSVIDCacheKey(params, "")] = svids
+	return nil
+}
@@ -0,0 +1,134 @@
+package workloadapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/jwtsvid"
+)
+
+func mustID(t *testing.T, s string) spiffeid.ID {
+	t.Helper()
+	id, err := spiffeid.FromString(s)
+	if err != nil {
+		t.Fatalf("spiffeid.FromString(%q) failed: %v", s, err)
+	}
+	return id
+}
+
+func pastExpiry() time.Time {
+	return time.Now().Add(-time.Minute)
+}
+
+func futureExpiry() time.Time {
+	return time.Now().Add(time.Hour)
+}
+
+func TestJWTSVIDCacheKeyDistinguishesParams(t *testing.T) {
+	subjectA := mustID(t, "spiffe://example.org/a")
+	subjectB := mustID(t, "spiffe://example.org/b")
+
+	base := jwtsvid.Params{Subject: subjectA, Audience: "aud1"}
+
+	cases := map[string]jwtsvid.Params{
+		"same params": base,
+		"different subject": {
+			Subject: subjectB, Audience: "aud1",
+		},
+		"different audience": {
+			Subject: subjectA, Audience: "aud2",
+		},
+		"extra audience": {
+			Subject: subjectA, Audience: "aud1", ExtraAudiences: []string{"aud2"},
+		},
+	}
+
+	baseKey := jwtSVIDCacheKey(base, "")
+	if got := jwtSVIDCacheKey(cases["same params"], ""); got != baseKey {
+		t.Fatalf("identical params produced different keys: %q != %q", got, baseKey)
+	}
+
+	for name, params := range cases {
+		if name == "same params" {
+			continue
+		}
+		if got := jwtSVIDCacheKey(params, ""); got == baseKey {
+			t.Errorf("%s: expected a different cache key than base, got the same %q", name, got)
+		}
+	}
+}
+
+func TestJWTSVIDCacheKeyDistinguishesHint(t *testing.T) {
+	params := jwtsvid.Params{Subject: mustID(t, "spiffe://example.org/workload"), Audience: "aud"}
+
+	plainKey := jwtSVIDCacheKey(params, "")
+	databaseKey := jwtSVIDCacheKey(params, "database")
+	mailKey := jwtSVIDCacheKey(params, "mail")
+
+	if plainKey == databaseKey || plainKey == mailKey || databaseKey == mailKey {
+		t.Fatalf("same params with different hints produced colliding keys: %q, %q, %q", plainKey, databaseKey, mailKey)
+	}
+}
+
+func TestMemoryCacheJWTSVIDExpiry(t *testing.T) {
+	params := jwtsvid.Params{Subject: mustID(t, "spiffe://example.org/workload"), Audience: "aud"}
+	c := NewMemoryCache()
+
+	if err := c.StoreJWTSVID(params, "", &jwtsvid.SVID{Expiry: pastExpiry()}); err != nil {
+		t.Fatalf("StoreJWTSVID failed: %v", err)
+	}
+
+	if _, err := c.LoadJWTSVID(params, ""); err != ErrCacheExpired {
+		t.Fatalf("LoadJWTSVID error = %v, want ErrCacheExpired", err)
+	}
+}
+
+func TestMemoryCacheJWTSVIDRoundTrip(t *testing.T) {
+	params := jwtsvid.Params{Subject: mustID(t, "spiffe://example.org/workload"), Audience: "aud"}
+	c := NewMemoryCache()
+
+	want := &jwtsvid.SVID{Expiry: futureExpiry()}
+	if err := c.StoreJWTSVID(params, "", want); err != nil {
+		t.Fatalf("StoreJWTSVID failed: %v", err)
+	}
+
+	got, err := c.LoadJWTSVID(params, "")
+	if err != nil {
+		t.Fatalf("LoadJWTSVID failed: %v", err)
+	}
+	if got != want {
+		t.Fatalf("LoadJWTSVID returned %v, want %v", got, want)
+	}
+}
+
+func TestMemoryCacheJWTSVIDWithHintDoesNotOverwriteOtherHints(t *testing.T) {
+	params := jwtsvid.Params{Subject: mustID(t, "spiffe://example.org/workload"), Audience: "aud"}
+	c := NewMemoryCache()
+
+	database := &jwtsvid.SVID{Expiry: futureExpiry()}
+	mail := &jwtsvid.SVID{Expiry: futureExpiry()}
+
+	if err := c.StoreJWTSVID(params, "database", database); err != nil {
+		t.Fatalf("StoreJWTSVID(database) failed: %v", err)
+	}
+	if err := c.StoreJWTSVID(params, "mail", mail); err != nil {
+		t.Fatalf("StoreJWTSVID(mail) failed: %v", err)
+	}
+
+	got, err := c.LoadJWTSVID(params, "database")
+	if err != nil {
+		t.Fatalf("LoadJWTSVID(database) failed: %v", err)
+	}
+	if got != database {
+		t.Fatalf("LoadJWTSVID(database) returned %v, want %v", got, database)
+	}
+
+	got, err = c.LoadJWTSVID(params, "mail")
+	if err != nil {
+		t.Fatalf("LoadJWTSVID(mail) failed: %v", err)
+	}
+	if got != mail {
+		t.Fatalf("LoadJWTSVID(mail) returned %v, want %v", got, mail)
+	}
+}
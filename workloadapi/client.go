@@ -9,6 +9,7 @@ import (
 
 	"github.com/spiffe/go-spiffe/v2/bundle/jwtbundle"
 	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/internal/backoff"
 	"github.com/spiffe/go-spiffe/v2/logger"
 	"github.com/spiffe/go-spiffe/v2/proto/spiffe/workload"
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
@@ -26,14 +27,14 @@ type Client struct {
 	conn     *grpc.ClientConn
 	wlClient workload.SpiffeWorkloadAPIClient
 	config   clientConfig
-	backoff  *backoff
+	backoff  *backoff.Backoff
 }
 
 // New dials the Workload API and returns a client.
 func New(ctx context.Context, options ...ClientOption) (*Client, error) {
 	c := &Client{
 		config:  defaultClientConfig(),
-		backoff: newBackoff(),
+		backoff: backoff.New(),
 	}
 	for _, opt := range options {
 		opt.configureClient(&c.config)
@@ -118,8 +119,30 @@ func (c *Client) FetchX509Bundles(ctx context.Context) (*x509bundle.Set, error)
 }
 
 // FetchX509Context fetches the X.509 context, which contains both X509-SVIDs
-// and X.509 bundles.
+// and X.509 bundles. If a Cache is configured and the Workload API cannot be
+// reached, the last X.509 context stored in the cache is returned instead.
 func (c *Client) FetchX509Context(ctx context.Context) (*X509Context, error) {
+	x509Context, err := c.fetchX509Context(ctx)
+	if err != nil {
+		if c.config.cache == nil {
+			return nil, err
+		}
+		cached, cacheErr := c.config.cache.LoadX509Context()
+		if cacheErr != nil {
+			return nil, err
+		}
+		return cached, nil
+	}
+
+	if c.config.cache != nil {
+		if err := c.config.cache.StoreX509Context(x509Context); err != nil {
+			c.config.log.Errorf("Failed to cache X.509 context: %v", err)
+		}
+	}
+	return x509Context, nil
+}
+
+func (c *Client) fetchX509Context(ctx context.Context) (*X509Context, error) {
 	ctx, cancel := context.WithCancel(withHeader(ctx))
 	defer cancel()
 
@@ -137,10 +160,15 @@ func (c *Client) FetchX509Context(ctx context.Context) (*X509Context, error) {
 }
 
 // WatchX509Context watches for updates to the X.509 context. The watcher
-// receives the updated X.509 context.
+// receives the updated X.509 context. If a Cache is configured and the
+// Workload API becomes unreachable, the watcher is updated with the last
+// X.509 context stored in the cache and its error callback is invoked with
+// ErrServedFromCache alongside the original error. The updates received by
+// the watch are not stored in the cache.
 func (c *Client) WatchX509Context(ctx context.Context, watcher X509ContextWatcher) error {
 	for {
 		err := c.watchX509Context(ctx, watcher)
+		c.serveX509ContextFromCache(watcher, err)
 		watcher.OnX509ContextWatchError(err)
 		err = c.handleWatchError(ctx, err)
 		if err != nil {
@@ -149,8 +177,43 @@ func (c *Client) WatchX509Context(ctx context.Context, watcher X509ContextWatche
 	}
 }
 
-// FetchJWTSVID fetches a JWT-SVID.
+func (c *Client) serveX509ContextFromCache(watcher X509ContextWatcher, watchErr error) {
+	if c.config.cache == nil {
+		return
+	}
+	cached, err := c.config.cache.LoadX509Context()
+	if err != nil {
+		return
+	}
+	watcher.OnX509ContextUpdate(cached)
+	watcher.OnX509ContextWatchError(fmt.Errorf("%w: %v", ErrServedFromCache, watchErr))
+}
+
+// FetchJWTSVID fetches a JWT-SVID. If a Cache is configured and the
+// Workload API cannot be reached, the last JWT-SVID cached for the given
+// parameters is returned instead.
 func (c *Client) FetchJWTSVID(ctx context.Context, params jwtsvid.Params) (*jwtsvid.SVID, error) {
+	svid, err := c.fetchJWTSVID(ctx, params)
+	if err != nil {
+		if c.config.cache == nil {
+			return nil, err
+		}
+		cached, cacheErr := c.config.cache.LoadJWTSVID(params, "")
+		if cacheErr != nil {
+			return nil, err
+		}
+		return cached, nil
+	}
+
+	if c.config.cache != nil {
+		if err := c.config.cache.StoreJWTSVID(params, "", svid); err != nil {
+			c.config.log.Errorf("Failed to cache JWT-SVID: %v", err)
+		}
+	}
+	return svid, nil
+}
+
+func (c *Client) fetchJWTSVID(ctx context.Context, params jwtsvid.Params) (*jwtsvid.SVID, error) {
 	ctx, cancel := context.WithCancel(withHeader(ctx))
 	defer cancel()
 
@@ -169,9 +232,211 @@ func (c *Client) FetchJWTSVID(ctx context.Context, params jwtsvid.Params) (*jwts
 	return jwtsvid.ParseInsecure(resp.Svids[0].Svid, audience)
 }
 
+// FetchJWTSVIDWithHint fetches the JWT-SVID among the response whose Hint
+// matches hint, e.g. to let a workload with multiple SPIFFE IDs request a
+// specific one ("the database one") instead of taking the first SVID
+// returned. If a Cache is configured and the Workload API cannot be
+// reached, the cached JWT-SVID for the given parameters is returned
+// instead.
+func (c *Client) FetchJWTSVIDWithHint(ctx context.Context, params jwtsvid.Params, hint string) (*jwtsvid.SVID, error) {
+	svid, err := c.fetchJWTSVIDWithHint(ctx, params, hint)
+	if err != nil {
+		if c.config.cache == nil {
+			return nil, err
+		}
+		cached, cacheErr := c.config.cache.LoadJWTSVID(params, hint)
+		if cacheErr != nil {
+			return nil, err
+		}
+		return cached, nil
+	}
+
+	if c.config.cache != nil {
+		if err := c.config.cache.StoreJWTSVID(params, hint, svid); err != nil {
+			c.config.log.Errorf("Failed to cache JWT-SVID: %v", err)
+		}
+	}
+	return svid, nil
+}
+
+func (c *Client) fetchJWTSVIDWithHint(ctx context.Context, params jwtsvid.Params, hint string) (*jwtsvid.SVID, error) {
+	ctx, cancel := context.WithCancel(withHeader(ctx))
+	defer cancel()
+
+	audience := append([]string{params.Audience}, params.ExtraAudiences...)
+	resp, err := c.wlClient.FetchJWTSVID(ctx, &workload.JWTSVIDRequest{
+		SpiffeId: params.Subject.String(),
+		Audience: audience,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, svid := range resp.Svids {
+		if svid.Hint != hint {
+			continue
+		}
+		return jwtsvid.ParseInsecure(svid.Svid, audience)
+	}
+	return nil, fmt.Errorf("no SVID in the response matched hint %q", hint)
+}
+
+// FetchJWTSVIDs fetches all JWT-SVIDs matching the given parameters. If a
+// Cache is configured and the Workload API cannot be reached, the last
+// JWT-SVIDs cached for the given parameters are returned instead.
+func (c *Client) FetchJWTSVIDs(ctx context.Context, params jwtsvid.Params) ([]*jwtsvid.SVID, error) {
+	svids, err := c.fetchJWTSVIDs(ctx, params)
+	if err != nil {
+		if c.config.cache == nil {
+			return nil, err
+		}
+		cached, cacheErr := c.config.cache.LoadJWTSVIDs(params)
+		if cacheErr != nil {
+			return nil, err
+		}
+		return cached, nil
+	}
+
+	if c.config.cache != nil {
+		if err := c.config.cache.StoreJWTSVIDs(params, svids); err != nil {
+			c.config.log.Errorf("Failed to cache JWT-SVIDs: %v", err)
+		}
+	}
+	return svids, nil
+}
+
+func (c *Client) fetchJWTSVIDs(ctx context.Context, params jwtsvid.Params) ([]*jwtsvid.SVID, error) {
+	ctx, cancel := context.WithCancel(withHeader(ctx))
+	defer cancel()
+
+	audience := append([]string{params.Audience}, params.ExtraAudiences...)
+	resp, err := c.wlClient.FetchJWTSVID(ctx, &workload.JWTSVIDRequest{
+		SpiffeId: params.Subject.String(),
+		Audience: audience,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseJWTSVIDs(resp, audience)
+}
+
+// parseJWTSVIDs parses every SVID in the response.
+func parseJWTSVIDs(resp *workload.JWTSVIDResponse, audience []string) ([]*jwtsvid.SVID, error) {
+	if len(resp.Svids) == 0 {
+		return nil, errors.New("there were no SVIDs in the response")
+	}
+
+	svids := make([]*jwtsvid.SVID, 0, len(resp.Svids))
+	for _, svid := range resp.Svids {
+		s, err := jwtsvid.ParseInsecure(svid.Svid, audience)
+		if err != nil {
+			return nil, err
+		}
+		svids = append(svids, s)
+	}
+	return svids, nil
+}
+
+// WatchJWTSVIDs watches for updates to the JWT-SVIDs matching the given
+// parameters. The Workload API does not expose a streaming endpoint for
+// JWT-SVIDs, so the client polls FetchJWTSVIDs on a timer keyed off the
+// soonest-expiring SVID, refreshing at half of its remaining lifetime. If a
+// Cache is configured and the Workload API becomes unreachable, the watcher
+// is updated with the last JWT-SVIDs stored in the cache and its error
+// callback is invoked with ErrServedFromCache alongside the original error.
+func (c *Client) WatchJWTSVIDs(ctx context.Context, params jwtsvid.Params, watcher JWTSVIDWatcher) error {
+	for {
+		next, err := c.watchJWTSVIDs(ctx, params, watcher)
+		if err != nil {
+			c.serveJWTSVIDsFromCache(params, watcher, err)
+			watcher.OnJWTSVIDsWatchError(err)
+			err = c.handleWatchError(ctx, err)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		select {
+		case <-time.After(next):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (c *Client) serveJWTSVIDsFromCache(params jwtsvid.Params, watcher JWTSVIDWatcher, watchErr error) {
+	if c.config.cache == nil {
+		return
+	}
+	cached, err := c.config.cache.LoadJWTSVIDs(params)
+	if err != nil {
+		return
+	}
+	watcher.OnJWTSVIDsUpdate(cached)
+	watcher.OnJWTSVIDsWatchError(fmt.Errorf("%w: %v", ErrServedFromCache, watchErr))
+}
+
+func (c *Client) watchJWTSVIDs(ctx context.Context, params jwtsvid.Params, watcher JWTSVIDWatcher) (time.Duration, error) {
+	c.config.log.Debugf("Watching JWT-SVIDs")
+	svids, err := c.fetchJWTSVIDs(ctx, params)
+	if err != nil {
+		return 0, err
+	}
+
+	c.backoff.Reset()
+	if c.config.cache != nil {
+		if err := c.config.cache.StoreJWTSVIDs(params, svids); err != nil {
+			c.config.log.Errorf("Failed to cache JWT-SVIDs: %v", err)
+		}
+	}
+	watcher.OnJWTSVIDsUpdate(svids)
+	return nextJWTSVIDsRefresh(svids), nil
+}
+
+// nextJWTSVIDsRefresh returns how long to wait before re-fetching the
+// JWT-SVIDs: half of the remaining lifetime of the soonest-expiring SVID.
+func nextJWTSVIDsRefresh(svids []*jwtsvid.SVID) time.Duration {
+	var soonest time.Time
+	for _, svid := range svids {
+		if soonest.IsZero() || svid.Expiry.Before(soonest) {
+			soonest = svid.Expiry
+		}
+	}
+
+	remaining := time.Until(soonest)
+	if remaining <= 0 {
+		return 0
+	}
+	return remaining / 2
+}
+
 // FetchJWTBundles fetches the JWT bundles for JWT-SVID validation, keyed
-// by a SPIFFE ID of the trust domain to which they belong.
+// by a SPIFFE ID of the trust domain to which they belong. If a Cache is
+// configured and the Workload API cannot be reached, the last JWT bundles
+// stored in the cache are returned instead.
 func (c *Client) FetchJWTBundles(ctx context.Context) (*jwtbundle.Set, error) {
+	bundles, err := c.fetchJWTBundles(ctx)
+	if err != nil {
+		if c.config.cache == nil {
+			return nil, err
+		}
+		cached, cacheErr := c.config.cache.LoadJWTBundles()
+		if cacheErr != nil {
+			return nil, err
+		}
+		return cached, nil
+	}
+
+	if c.config.cache != nil {
+		if err := c.config.cache.StoreJWTBundles(bundles); err != nil {
+			c.config.log.Errorf("Failed to cache JWT bundles: %v", err)
+		}
+	}
+	return bundles, nil
+}
+
+func (c *Client) fetchJWTBundles(ctx context.Context) (*jwtbundle.Set, error) {
 	ctx, cancel := context.WithCancel(withHeader(ctx))
 	defer cancel()
 
@@ -189,10 +454,15 @@ func (c *Client) FetchJWTBundles(ctx context.Context) (*jwtbundle.Set, error) {
 }
 
 // WatchJWTBundles watches for changes to the JWT bundles. The watcher receives
-// the updated JWT bundles.
+// the updated JWT bundles. If a Cache is configured and the Workload API
+// becomes unreachable, the watcher is updated with the last JWT bundles
+// stored in the cache and its error callback is invoked with
+// ErrServedFromCache alongside the original error. The updates received by
+// the watch are not stored in the cache.
 func (c *Client) WatchJWTBundles(ctx context.Context, watcher JWTBundleWatcher) error {
 	for {
 		err := c.watchJWTBundles(ctx, watcher)
+		c.serveJWTBundlesFromCache(watcher, err)
 		watcher.OnJWTBundlesWatchError(err)
 		err = c.handleWatchError(ctx, err)
 		if err != nil {
@@ -201,6 +471,18 @@ func (c *Client) WatchJWTBundles(ctx context.Context, watcher JWTBundleWatcher)
 	}
 }
 
+func (c *Client) serveJWTBundlesFromCache(watcher JWTBundleWatcher, watchErr error) {
+	if c.config.cache == nil {
+		return
+	}
+	cached, err := c.config.cache.LoadJWTBundles()
+	if err != nil {
+		return
+	}
+	watcher.OnJWTBundlesUpdate(cached)
+	watcher.OnJWTBundlesWatchError(fmt.Errorf("%w: %v", ErrServedFromCache, watchErr))
+}
+
 // ValidateJWTSVID validates the JWT-SVID token. The parsed and validated
 // JWT-SVID is returned.
 func (c *Client) ValidateJWTSVID(ctx context.Context, token, audience string) (*jwtsvid.SVID, error) {
@@ -249,10 +531,10 @@ func (c *Client) handleWatchError(ctx context.Context, err error) error {
 	}
 
 	c.config.log.Errorf("Failed to watch the Workload API: %v", err)
-	backoff := c.backoff.Duration()
-	c.config.log.Debugf("Retrying watch in %s", backoff)
+	delay := c.backoff.Duration()
+	c.config.log.Debugf("Retrying watch in %s", delay)
 	select {
-	case <-time.After(backoff):
+	case <-time.After(delay):
 		return nil
 
 	case <-ctx.Done():
@@ -336,6 +618,17 @@ type JWTBundleWatcher interface {
 	OnJWTBundlesWatchError(error)
 }
 
+// JWTSVIDWatcher receives JWT-SVID updates from the Workload API.
+type JWTSVIDWatcher interface {
+	// OnJWTSVIDsUpdate is called with the latest JWT-SVIDs retrieved from
+	// the Workload API.
+	OnJWTSVIDsUpdate([]*jwtsvid.SVID)
+
+	// OnJWTSVIDsWatchError is called when there is a problem fetching the
+	// latest JWT-SVIDs from the Workload API.
+	OnJWTSVIDsWatchError(error)
+}
+
 func withHeader(ctx context.Context) context.Context {
 	header := metadata.Pairs("workload.spiffe.io", "true")
 	return metadata.NewOutgoingContext(ctx, header)
@@ -0,0 +1,69 @@
+package workloadapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/proto/spiffe/workload"
+	"github.com/spiffe/go-spiffe/v2/svid/jwtsvid"
+)
+
+func TestParseJWTSVIDsNoSVIDsFails(t *testing.T) {
+	_, err := parseJWTSVIDs(&workload.JWTSVIDResponse{}, []string{"aud"})
+	if err == nil {
+		t.Fatal("parseJWTSVIDs() with no SVIDs in the response succeeded, want error")
+	}
+}
+
+func TestNextJWTSVIDsRefresh(t *testing.T) {
+	now := time.Now()
+
+	cases := map[string]struct {
+		expiries []time.Duration
+		wantZero bool
+	}{
+		"no SVIDs": {
+			expiries: nil,
+			wantZero: true,
+		},
+		"already expired": {
+			expiries: []time.Duration{-time.Minute},
+			wantZero: true,
+		},
+		"single SVID": {
+			expiries: []time.Duration{10 * time.Minute},
+		},
+		"soonest of several wins": {
+			expiries: []time.Duration{time.Hour, 10 * time.Minute, 30 * time.Minute},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			svids := make([]*jwtsvid.SVID, 0, len(c.expiries))
+			var soonest time.Time
+			for _, d := range c.expiries {
+				expiry := now.Add(d)
+				if soonest.IsZero() || expiry.Before(soonest) {
+					soonest = expiry
+				}
+				svids = append(svids, &jwtsvid.SVID{Expiry: expiry})
+			}
+
+			got := nextJWTSVIDsRefresh(svids)
+			if c.wantZero {
+				if got != 0 {
+					t.Fatalf("nextJWTSVIDsRefresh() = %s, want 0", got)
+				}
+				return
+			}
+
+			wantRemaining := time.Until(soonest)
+			wantHalf := wantRemaining / 2
+			// Allow a small amount of drift from the time.Now() calls above.
+			if got <= 0 || got > wantHalf+time.Second || got < wantHalf-time.Second {
+				t.Fatalf("nextJWTSVIDsRefresh() = %s, want ~%s", got, wantHalf)
+			}
+		})
+	}
+}
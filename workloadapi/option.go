@@ -0,0 +1,49 @@
+package workloadapi
+
+import (
+	"github.com/spiffe/go-spiffe/v2/logger"
+
+	"google.golang.org/grpc"
+)
+
+type clientConfig struct {
+	address     string
+	dialOptions []grpc.DialOption
+	log         logger.Logger
+	cache       Cache
+}
+
+// ClientOption is an option used when creating a new Client.
+type ClientOption interface {
+	configureClient(*clientConfig)
+}
+
+type clientOptionFunc func(*clientConfig)
+
+func (fn clientOptionFunc) configureClient(c *clientConfig) {
+	fn(c)
+}
+
+// WithAddr provides an address to use when dialing the Workload API. The
+// value is in URI form, e.g. "unix:///path/to/workload.sock". If not
+// provided, the client looks for an address via GetDefaultAddress.
+func WithAddr(addr string) ClientOption {
+	return clientOptionFunc(func(c *clientConfig) {
+		c.address = addr
+	})
+}
+
+// WithDialOptions provides extra gRPC dial options when dialing the
+// Workload API.
+func WithDialOptions(options ...grpc.DialOption) ClientOption {
+	return clientOptionFunc(func(c *clientConfig) {
+		c.dialOptions = append(c.dialOptions, options...)
+	})
+}
+
+// WithLogger provides a logger to the client.
+func WithLogger(log logger.Logger) ClientOption {
+	return clientOptionFunc(func(c *clientConfig) {
+		c.log = log
+	})
+}
@@ -0,0 +1,142 @@
+package workloadapi
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/internal/backoff"
+	"github.com/spiffe/go-spiffe/v2/svid/jwtsvid"
+)
+
+// RotatingJWTSource is a jwtsvid.Source backed by a JWT-SVID fetched from
+// the Workload API. It refreshes the SVID in the background as it nears
+// expiry, giving callers the same self-rotating semantics that JWTSource
+// provides for bundles, but for the SVID itself.
+type RotatingJWTSource struct {
+	client  *Client
+	params  jwtsvid.Params
+	skew    time.Duration
+	backoff *backoff.Backoff
+
+	mu   sync.RWMutex
+	svid *jwtsvid.SVID
+
+	cancel context.CancelFunc
+	closed chan struct{}
+}
+
+// RotatingJWTSourceOption customizes a RotatingJWTSource created by
+// NewRotatingJWTSource.
+type RotatingJWTSourceOption interface {
+	configureRotatingJWTSource(*rotatingJWTSourceConfig)
+}
+
+type rotatingJWTSourceConfig struct {
+	skew time.Duration
+}
+
+type rotationSkewOption time.Duration
+
+func (o rotationSkewOption) configureRotatingJWTSource(c *rotatingJWTSourceConfig) {
+	c.skew = time.Duration(o)
+}
+
+// WithRotationSkew sets how far ahead of its expiry the SVID is refreshed.
+// If unset (or zero), the SVID is refreshed halfway through its remaining
+// lifetime, matching the cadence WatchJWTSVIDs uses.
+func WithRotationSkew(skew time.Duration) RotatingJWTSourceOption {
+	return rotationSkewOption(skew)
+}
+
+// NewRotatingJWTSource fetches a JWT-SVID for params and returns a
+// jwtsvid.Source backed by it that keeps the SVID fresh in the background
+// for as long as ctx is not done.
+func NewRotatingJWTSource(ctx context.Context, client *Client, params jwtsvid.Params, options ...RotatingJWTSourceOption) (*RotatingJWTSource, error) {
+	config := rotatingJWTSourceConfig{}
+	for _, opt := range options {
+		opt.configureRotatingJWTSource(&config)
+	}
+
+	svid, err := client.FetchJWTSVID(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	rotateCtx, cancel := context.WithCancel(ctx)
+	s := &RotatingJWTSource{
+		client:  client,
+		params:  params,
+		skew:    config.skew,
+		backoff: backoff.New(),
+		svid:    svid,
+		cancel:  cancel,
+		closed:  make(chan struct{}),
+	}
+	go s.rotate(rotateCtx)
+	return s, nil
+}
+
+// GetJWTSVID returns the current JWT-SVID.
+func (s *RotatingJWTSource) GetJWTSVID() (*jwtsvid.SVID, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.svid, nil
+}
+
+// FetchJWTSVID returns the current JWT-SVID, satisfying jwtsvid.Source. The
+// params argument is ignored: the source only holds the JWT-SVID for the
+// params it was created with.
+func (s *RotatingJWTSource) FetchJWTSVID(ctx context.Context, params jwtsvid.Params) (*jwtsvid.SVID, error) {
+	return s.GetJWTSVID()
+}
+
+// Close stops the background rotation.
+func (s *RotatingJWTSource) Close() {
+	s.cancel()
+	<-s.closed
+}
+
+func (s *RotatingJWTSource) rotate(ctx context.Context) {
+	defer close(s.closed)
+	for {
+		select {
+		case <-time.After(s.nextRefresh()):
+		case <-ctx.Done():
+			return
+		}
+
+		svid, err := s.client.FetchJWTSVID(ctx, s.params)
+		if err != nil {
+			s.client.config.log.Errorf("Failed to rotate JWT-SVID: %v", err)
+			select {
+			case <-time.After(s.backoff.Duration()):
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		s.backoff.Reset()
+		s.mu.Lock()
+		s.svid = svid
+		s.mu.Unlock()
+	}
+}
+
+func (s *RotatingJWTSource) nextRefresh() time.Duration {
+	s.mu.RLock()
+	expiry := s.svid.Expiry
+	s.mu.RUnlock()
+
+	remaining := time.Until(expiry)
+	if s.skew > 0 {
+		remaining -= s.skew
+	} else {
+		remaining /= 2
+	}
+	if remaining <= 0 {
+		return 0
+	}
+	return remaining
+}
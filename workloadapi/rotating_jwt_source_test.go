@@ -0,0 +1,62 @@
+package workloadapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/svid/jwtsvid"
+)
+
+func TestRotatingJWTSourceNextRefresh(t *testing.T) {
+	cases := map[string]struct {
+		remaining time.Duration
+		skew      time.Duration
+		wantZero  bool
+		wantHalf  bool
+	}{
+		"no skew splits the remaining lifetime": {
+			remaining: 10 * time.Minute,
+			wantHalf:  true,
+		},
+		"skew refreshes a fixed duration before expiry": {
+			remaining: 10 * time.Minute,
+			skew:      2 * time.Minute,
+		},
+		"already past the skew deadline refreshes immediately": {
+			remaining: time.Minute,
+			skew:      10 * time.Minute,
+			wantZero:  true,
+		},
+		"already expired refreshes immediately": {
+			remaining: -time.Minute,
+			wantZero:  true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := &RotatingJWTSource{
+				skew: c.skew,
+				svid: &jwtsvid.SVID{Expiry: time.Now().Add(c.remaining)},
+			}
+
+			got := s.nextRefresh()
+			switch {
+			case c.wantZero:
+				if got != 0 {
+					t.Fatalf("nextRefresh() = %s, want 0", got)
+				}
+			case c.wantHalf:
+				wantHalf := c.remaining / 2
+				if got <= 0 || got > wantHalf+time.Second || got < wantHalf-time.Second {
+					t.Fatalf("nextRefresh() = %s, want ~%s", got, wantHalf)
+				}
+			default:
+				want := c.remaining - c.skew
+				if got <= 0 || got > want+time.Second || got < want-time.Second {
+					t.Fatalf("nextRefresh() = %s, want ~%s", got, want)
+				}
+			}
+		})
+	}
+}